@@ -8,7 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/robotomize/go-allure/internal/slice"
@@ -18,6 +20,43 @@ type NestedTest struct {
 	Value    Test
 	Children []NestedTest
 	Log      []byte
+
+	// Panic holds the parsed "panic: ..." stack trace found in Log, if any.
+	Panic *PanicInfo
+	// DataRace holds the parsed "WARNING: DATA RACE" block found in Log, if
+	// any.
+	DataRace *RaceInfo
+}
+
+// StackFrame is a single frame of a goroutine stack trace, as reported in a
+// panic or a -race detector block.
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// PanicInfo is the parsed contents of a "panic: ..." stack trace captured
+// from a test's output.
+type PanicInfo struct {
+	Message   string
+	Goroutine string
+	Stack     []StackFrame
+}
+
+// RaceAccess is one of the two conflicting accesses reported in a
+// "WARNING: DATA RACE" block.
+type RaceAccess struct {
+	// Operation is e.g. "Write", "Read", "Previous write" or "Previous read".
+	Operation string
+	Goroutine string
+	Stack     []StackFrame
+}
+
+// RaceInfo is the parsed contents of a "WARNING: DATA RACE" block captured
+// from a test's output.
+type RaceInfo struct {
+	Accesses [2]RaceAccess
 }
 
 type Set struct {
@@ -25,38 +64,274 @@ type Set struct {
 	Tests []NestedTest
 }
 
-func NewReader(r io.Reader) *Reader {
-	return &Reader{r: bufio.NewScanner(r)}
+// DefaultMaxDepth is the recursion depth Reader.walk enforces when the
+// Reader is constructed without an explicit WithMaxDepth option.
+const DefaultMaxDepth = 10000
+
+// DefaultMaxLineSize is the per-line buffer limit Reader scans with when the
+// Reader is constructed without an explicit WithMaxLineSize option. It is
+// larger than bufio.Scanner's own 64KB default, which truncates long t.Log
+// output from real CI runs.
+const DefaultMaxLineSize = 1 << 20 // 1MiB
+
+// startLineBufSize is the initial size of the scanning buffer; it grows up
+// to MaxLineSize as bufio.Scanner needs more room for a single line.
+const startLineBufSize = 64 * 1024
+
+// ErrMaxDepthExceeded is joined into Set.Err when walk gives up on a subtree
+// because it exceeded the Reader's configured MaxDepth. walk returns it
+// wrapped together with the Package/TestName key of the node where recursion
+// was cut off.
+var ErrMaxDepthExceeded = errors.New("gotest: max recursion depth exceeded")
+
+// ReaderOption configures a Reader at construction time.
+type ReaderOption func(*Reader)
+
+// WithMaxDepth overrides the depth Reader.walk will recurse into the prefix
+// tree before it stops descending into a subtree. The prefix tree is built
+// from Package + "/" + TestName keys parsed out of untrusted `go test -json`
+// output, so a pathological or malformed input (thousands of nested
+// subtests, a crafted deeply-slashed test name) could otherwise exhaust the
+// goroutine stack.
+func WithMaxDepth(n int) ReaderOption {
+	return func(r *Reader) {
+		r.maxDepth = n
+	}
+}
+
+// WithMaxLineSize overrides the largest single line (one `go test -json`
+// event) the Reader will scan. Raise it when test output lines exceed the
+// default.
+func WithMaxLineSize(n int) ReaderOption {
+	return func(r *Reader) {
+		r.maxLineSize = n
+	}
+}
+
+// MatchFunc reports whether a test identified by its package and name should
+// be kept. It is an escape hatch for callers who want filtering logic beyond
+// what WithInclude/WithExclude's glob patterns can express; when set it
+// entirely replaces them.
+type MatchFunc func(pkg, name string) bool
+
+// WithInclude keeps only tests whose Package + "/" + TestName key matches at
+// least one of the given glob patterns (or one of their parent subtest
+// segments). Patterns support "*" (any run of characters within a path
+// segment), "**" (any run of characters, including "/"), and "?" (a single
+// character other than "/"). Patterns are compiled once, by NewReader.
+func WithInclude(patterns ...string) ReaderOption {
+	return func(r *Reader) {
+		r.include = append(r.include, patterns...)
+	}
+}
+
+// WithExclude drops tests whose Package + "/" + TestName key matches any of
+// the given glob patterns; see WithInclude for the supported syntax.
+// Children of an excluded parent test are excluded too.
+func WithExclude(patterns ...string) ReaderOption {
+	return func(r *Reader) {
+		r.exclude = append(r.exclude, patterns...)
+	}
+}
+
+// WithMatchFunc overrides Include/Exclude glob matching with custom logic.
+func WithMatchFunc(fn MatchFunc) ReaderOption {
+	return func(r *Reader) {
+		r.matchFunc = fn
+	}
+}
+
+func NewReader(r io.Reader, opts ...ReaderOption) *Reader {
+	rd := &Reader{maxDepth: DefaultMaxDepth, maxLineSize: DefaultMaxLineSize}
+	for _, opt := range opts {
+		opt(rd)
+	}
+
+	initBufSize := startLineBufSize
+	if rd.maxLineSize < initBufSize {
+		initBufSize = rd.maxLineSize
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, initBufSize), rd.maxLineSize)
+	rd.r = sc
+
+	rd.includeRe = compileGlobs(rd.include)
+	rd.excludeRe = compileGlobs(rd.exclude)
+
+	return rd
 }
 
 type Reader struct {
-	r *bufio.Scanner
+	r           *bufio.Scanner
+	maxDepth    int
+	maxLineSize int
+
+	include   []string
+	exclude   []string
+	includeRe []*regexp.Regexp
+	excludeRe []*regexp.Regexp
+	matchFunc MatchFunc
 }
 
-// ReadAll function on the Reader struct that takes in a context.Context and returns a Set and an error.
-func (r *Reader) ReadAll(ctx context.Context) (Set, error) {
-	var errs []error
+// match reports whether a test identified by pkg and name should be kept. A
+// test is kept if matchFunc (when set) says so, or, using the Include/
+// Exclude globs: it isn't excluded itself (nor is any of its parent subtest
+// segments), and either no Include patterns were given or it (or a parent
+// segment) matches one.
+func (r *Reader) match(pkg, name string) bool {
+	if r.matchFunc != nil {
+		return r.matchFunc(pkg, name)
+	}
 
-	prefix := &prefixNode{}
+	segments := strings.Split(name, "/")
 
-	// Iterate through each line in the scanner.
-	// If the context is done, return an empty Set and the context error.
-	// Parse the line as a JSON object and update the corresponding Test object in the prefix tree.
-	for r.r.Scan() {
-		select {
-		case <-ctx.Done():
-			return Set{}, ctx.Err()
+	for i := range segments {
+		key := pkg + "/" + strings.Join(segments[:i+1], "/")
+		if matchAny(r.excludeRe, key) {
+			return false
+		}
+	}
+
+	if len(r.includeRe) == 0 {
+		return true
+	}
+
+	for i := range segments {
+		key := pkg + "/" + strings.Join(segments[:i+1], "/")
+		if matchAny(r.includeRe, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileGlobs compiles each pattern with globToRegexp, silently dropping
+// any pattern that fails to compile.
+func compileGlobs(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			continue
+		}
+
+		compiled = append(compiled, re)
+	}
+
+	return compiled
+}
+
+// globToRegexp compiles a gobwas/glob-style pattern into an anchored regular
+// expression. It supports "*" (any run of characters within a path
+// segment), "**" (any run of characters, including "/"), and "?" (a single
+// character other than "/"); everything else is matched literally.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteByte('^')
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
 		default:
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
 		}
+	}
 
-		line := r.r.Bytes()
+	b.WriteByte('$')
 
-		var row Entry
-		if err := json.Unmarshal(line, &row); err != nil {
-			errs = append(errs, fmt.Errorf("json.Unmarshal: %w", err))
+	return regexp.Compile(b.String())
+}
+
+// matchAny reports whether key matches any of the given compiled patterns.
+func matchAny(patterns []*regexp.Regexp, key string) bool {
+	for _, re := range patterns {
+		if re.MatchString(key) {
+			return true
 		}
+	}
+
+	return false
+}
+
+// ReadAll reads the whole `go test -json` stream into memory and returns it
+// as a single Set. It is a thin wrapper around Stream for callers that don't
+// need incremental results; for long CI runs prefer Stream so top-level
+// tests can be consumed (and released) as they resolve.
+func (r *Reader) ReadAll(ctx context.Context) (Set, error) {
+	out, errCh := r.Stream(ctx)
+
+	result := Set{Tests: make([]NestedTest, 0)}
+	for tc := range out {
+		result.Tests = append(result.Tests, tc)
+	}
+
+	if err := <-errCh; err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return Set{}, err
+		}
+
+		result.Err = err
+	}
+
+	return result, nil
+}
+
+// Stream reads the underlying `go test -json` output incrementally and
+// returns a channel of NestedTest, each emitted as soon as its top-level
+// test's terminal --- PASS/FAIL/SKIP action has been observed, and a channel
+// that carries a single joined error once reading finishes. A resolved
+// top-level test is dropped from the internal prefix tree immediately after
+// it's emitted, so long CI runs don't have to hold the whole run in memory.
+//
+// Both channels are closed once the underlying reader is exhausted, the
+// context is cancelled, or the Scanner's line buffer is exceeded.
+func (r *Reader) Stream(ctx context.Context) (<-chan NestedTest, <-chan error) {
+	out := make(chan NestedTest)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+
+		var errs []error
+
+		prefix := &prefixNode{}
+
+		for r.r.Scan() {
+			select {
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			default:
+			}
+
+			line := r.r.Bytes()
+
+			var row Entry
+			if err := json.Unmarshal(line, &row); err != nil {
+				errs = append(errs, fmt.Errorf("json.Unmarshal: %w", err))
+				continue
+			}
+
+			if len(row.TestName) == 0 {
+				continue
+			}
+
+			if !r.match(row.Package, row.TestName) {
+				continue
+			}
 
-		if len(row.TestName) > 0 {
 			key := row.Package + "/" + row.TestName
 
 			tc, ok := prefix.find(key)
@@ -70,37 +345,121 @@ func (r *Reader) ReadAll(ctx context.Context) (Set, error) {
 			}
 
 			tc.Update(row)
+
+			// A key only represents a fully resolved subtree once it's a
+			// root of the prefix tree itself: an ordinary subtest sits
+			// under its parent and is picked up when the parent resolves,
+			// but an Include/Exclude filter can drop a parent while still
+			// keeping one of its subtests, which promotes that subtest's
+			// key to a root even though it still contains "/".
+			if !isTerminalAction(row.Action) || !isRootChild(prefix, key) {
+				continue
+			}
+
+			resolved, err, ok := r.resolve(prefix, key)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			if !ok {
+				continue
+			}
+
+			select {
+			case out <- resolved:
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			}
 		}
-	}
 
-	result := Set{
-		Err: errors.Join(errs...),
-	}
+		if err := r.r.Err(); err != nil {
+			errs = append(errs, err)
+		}
 
-	// Create a slice to hold NestedTest instances.
-	// Iterate through each child in the prefix tree.
-	// If the walk function returns a NestedTest and true, append the NestedTest to the testCases slice.
-	testCases := make([]NestedTest, 0, len(prefix.Children))
-	for _, nod := range prefix.Children {
-		if tc, ok := r.walk(nod, newPrefixLog()); ok {
-			testCases = append(testCases, tc)
+		// The stream can end (EOF, or a line exceeding MaxLineSize) with
+		// tests still sitting in the prefix tree: one that never reached a
+		// terminal action (crashed, hung, or the process exited mid-run),
+		// or a sibling of one that did. Flush whatever is left so it still
+		// shows up in the result instead of silently vanishing.
+		for len(prefix.Children) > 0 {
+			nod := prefix.Children[0]
+			prefix.Children = prefix.Children[1:]
+
+			tc, ok, err := r.walk(nod, newPrefixLog(), 0)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			if !ok {
+				continue
+			}
+
+			select {
+			case out <- tc:
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			}
+		}
+
+		if err := errors.Join(errs...); err != nil {
+			errCh <- err
+		}
+	}()
+
+	return out, errCh
+}
+
+// isRootChild reports whether key names a direct child of root, i.e. a
+// top-level test rather than a subtest still nested under a parent. It does
+// not check whether the test has reached a terminal action.
+func isRootChild(root *prefixNode, key string) bool {
+	for _, nod := range root.Children {
+		if nod.Key == key {
+			return true
 		}
 	}
 
-	result.Tests = make([]NestedTest, len(testCases))
-	copy(result.Tests, testCases)
+	return false
+}
 
-	return result, nil
+// resolve removes the top-level child matching key from root and walks it,
+// releasing the rest of its subtree from the prefix tree in the process.
+func (r *Reader) resolve(root *prefixNode, key string) (NestedTest, error, bool) {
+	for idx, nod := range root.Children {
+		if nod.Key != key {
+			continue
+		}
+
+		root.Children = append(root.Children[:idx], root.Children[idx+1:]...)
+
+		tc, ok, err := r.walk(nod, newPrefixLog(), 0)
+
+		return tc, err, ok
+	}
+
+	return NestedTest{}, nil, false
 }
 
-// The walk function takes in a prefix node and a prefix log as parameters
-// and returns a NestedTest struct and a boolean value.
+// isTerminalAction reports whether action marks a test as finished.
+func isTerminalAction(action string) bool {
+	return action == ActionPass || action == ActionFail || action == ActionSkip
+}
 
-func (r *Reader) walk(node *prefixNode, prefix *prefixLog) (NestedTest, bool) {
+// The walk function takes in a prefix node, a prefix log, and the current
+// recursion depth as parameters and returns a NestedTest struct, a boolean
+// value, and an error.
+//
+// Once depth reaches the Reader's maxDepth, walk stops descending into the
+// node's children: it still attaches whatever log has accumulated so far and
+// returns ErrMaxDepthExceeded identifying the test key where it gave up,
+// rather than recursing further and risking stack exhaustion.
+func (r *Reader) walk(node *prefixNode, prefix *prefixLog, depth int) (NestedTest, bool, error) {
 	var testCase NestedTest
 
 	if node == nil {
-		return testCase, false
+		return testCase, false, nil
 	}
 
 	testCase.Value = *node.Value
@@ -133,12 +492,22 @@ func (r *Reader) walk(node *prefixNode, prefix *prefixLog) (NestedTest, bool) {
 	prefix.incrPrefix()
 	defer prefix.decrPrefix()
 
-	// Iterate through each child in the node's children field.
-	// If the recursive walk function returns a testCase and true,
-	// append the returned testCase to the current testCase's children field.
-	for _, nod := range node.Children {
-		if child, ok := r.walk(nod, prefix.copy()); ok {
-			testCase.Children = append(testCase.Children, child)
+	// Iterate through each child in the node's children field, unless this
+	// node already sits at maxDepth: past that point we stop descending and
+	// report the offending key instead of recursing further.
+	var walkErr error
+	if depth >= r.maxDepth {
+		walkErr = fmt.Errorf("%w: %s", ErrMaxDepthExceeded, node.Value.Package+"/"+node.Value.Name)
+	} else {
+		for _, nod := range node.Children {
+			child, ok, err := r.walk(nod, prefix.copy(), depth+1)
+			if err != nil {
+				walkErr = errors.Join(walkErr, err)
+			}
+
+			if ok {
+				testCase.Children = append(testCase.Children, child)
+			}
 		}
 	}
 
@@ -146,13 +515,13 @@ func (r *Reader) walk(node *prefixNode, prefix *prefixLog) (NestedTest, bool) {
 	// Seek the reader to the current prefix position in the buffer.
 	reader := bytes.NewReader(prefix.buf.Bytes())
 	if _, err := reader.Seek(int64(prefix.pos), io.SeekCurrent); err != nil {
-		return NestedTest{}, false
+		return NestedTest{}, false, err
 	}
 
 	// Read all the bytes from the reader and convert it into a string slice.
 	all, err := io.ReadAll(reader)
 	if err != nil {
-		return NestedTest{}, false
+		return NestedTest{}, false, err
 	}
 
 	// Convert bytes to strings
@@ -187,6 +556,16 @@ func (r *Reader) walk(node *prefixNode, prefix *prefixLog) (NestedTest, bool) {
 		}
 	}
 
+	// A subtree with no --- PASS/FAIL/SKIP line anywhere in it (a test that
+	// panicked or hung before ever printing its own result row) has nothing
+	// to dedent against, and leaving mx at its huge sentinel would make the
+	// strings.Replace below treat it as "strip every occurrence," mangling
+	// any incidental 4-space run inside a panic message, struct dump, or
+	// stack frame rather than just the prefix indentation.
+	if len(mark) == 0 {
+		mx = 0
+	}
+
 	// Sort the mark slice by the number of whitespace indents.
 	sort.Slice(
 		mark, func(i, j int) bool {
@@ -203,7 +582,111 @@ func (r *Reader) walk(node *prefixNode, prefix *prefixLog) (NestedTest, bool) {
 
 	testCase.Log = log
 
-	return testCase, true
+	// A panic or a parent test panicking under it can hang its children and
+	// skip the terminal --- FAIL action entirely, which would otherwise show
+	// up in Allure as a passing or unknown test. Detect both from the
+	// resolved log and, for a panic with no recorded status, synthesize a
+	// failure so it surfaces.
+	if p := detectPanic(testCase.Log); p != nil {
+		testCase.Panic = p
+
+		if testCase.Value.Status == "" {
+			testCase.Value.Status = ActionFail
+		}
+	}
+
+	testCase.DataRace = detectDataRace(testCase.Log)
+
+	return testCase, true, walkErr
+}
+
+var (
+	panicHeaderRe = regexp.MustCompile(`(?m)^panic: (.*)$`)
+	goroutineRe   = regexp.MustCompile(`(?m)^goroutine (\d+) \[[^]]*]:$`)
+	stackFrameRe  = regexp.MustCompile(`^\s*(.+\.go):(\d+)(?:\s.*)?$`)
+	raceHeaderRe  = regexp.MustCompile(`WARNING: DATA RACE`)
+	raceAccessRe  = regexp.MustCompile(`(?m)^(Write|Read|Previous write|Previous read) at 0x[0-9a-f]+ by goroutine (\d+):$`)
+)
+
+// detectPanic scans log for a "panic: ..." prelude and, if found, the
+// goroutine stack trace that follows it.
+func detectPanic(log []byte) *PanicInfo {
+	text := string(log)
+
+	loc := panicHeaderRe.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return nil
+	}
+
+	info := &PanicInfo{Message: text[loc[2]:loc[3]]}
+
+	rest := text[loc[1]:]
+	gLoc := goroutineRe.FindStringSubmatchIndex(rest)
+	if gLoc == nil {
+		return info
+	}
+
+	info.Goroutine = rest[gLoc[2]:gLoc[3]]
+	info.Stack = parseStackFrames(rest[gLoc[1]:])
+
+	return info
+}
+
+// detectDataRace scans log for a "WARNING: DATA RACE" block and, if found,
+// the two conflicting access sites it reports.
+func detectDataRace(log []byte) *RaceInfo {
+	text := string(log)
+
+	if !raceHeaderRe.MatchString(text) {
+		return nil
+	}
+
+	matches := raceAccessRe.FindAllStringSubmatchIndex(text, 2)
+	if len(matches) < 2 {
+		return nil
+	}
+
+	var info RaceInfo
+	for i, m := range matches[:2] {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+
+		info.Accesses[i] = RaceAccess{
+			Operation: text[m[2]:m[3]],
+			Goroutine: text[m[4]:m[5]],
+			Stack:     parseStackFrames(text[m[1]:end]),
+		}
+	}
+
+	return &info
+}
+
+// parseStackFrames reads consecutive (function, file:line) line pairs off
+// the front of text, stopping at the first line that doesn't fit the
+// pattern or at a blank line.
+func parseStackFrames(text string) []StackFrame {
+	lines := strings.Split(strings.TrimPrefix(text, "\n"), "\n")
+
+	var frames []StackFrame
+	for i := 0; i+1 < len(lines); i++ {
+		fn := strings.TrimSpace(lines[i])
+		if fn == "" {
+			break
+		}
+
+		m := stackFrameRe.FindStringSubmatch(lines[i+1])
+		if m == nil {
+			break
+		}
+
+		line, _ := strconv.Atoi(m[2])
+		frames = append(frames, StackFrame{Function: fn, File: m[1], Line: line})
+		i++
+	}
+
+	return frames
 }
 
 func newPrefixLog() *prefixLog {
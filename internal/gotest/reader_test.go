@@ -0,0 +1,522 @@
+package gotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildChain returns a prefix tree that is a single chain of n nested
+// subtests, each one child of the previous, so walk has to recurse n levels
+// deep to reach the bottom.
+func buildChain(n int) *prefixNode {
+	root := &prefixNode{}
+
+	cur := root
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("pkg/Test%d", i)
+		child := &prefixNode{
+			Key:   key,
+			Value: &Test{Name: fmt.Sprintf("Test%d", i), Package: "pkg"},
+		}
+		cur.Children = append(cur.Children, child)
+		cur = child
+	}
+
+	return root
+}
+
+// TestReader_WalkMaxDepth verifies that walk stops descending once it hits
+// the configured MaxDepth instead of recursing through the whole chain, and
+// that it reports ErrMaxDepthExceeded rather than overflowing the stack.
+func TestReader_WalkMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	const chainDepth = 50000
+	const maxDepth = 100
+
+	root := buildChain(chainDepth)
+	r := NewReader(nil, WithMaxDepth(maxDepth))
+
+	_, ok, err := r.walk(root.Children[0], newPrefixLog(), 0)
+	if !ok {
+		t.Fatalf("got ok: %v, want: %v", ok, true)
+	}
+
+	if !errors.Is(err, ErrMaxDepthExceeded) {
+		t.Fatalf("got err: %v, want: %v", err, ErrMaxDepthExceeded)
+	}
+}
+
+// TestReader_WalkWithinMaxDepth verifies that a chain shallower than
+// MaxDepth walks through entirely and reports no error.
+func TestReader_WalkWithinMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	root := buildChain(10)
+	r := NewReader(nil, WithMaxDepth(DefaultMaxDepth))
+
+	tc, ok, err := r.walk(root.Children[0], newPrefixLog(), 0)
+	if !ok {
+		t.Fatalf("got ok: %v, want: %v", ok, true)
+	}
+
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	depth := 0
+	for cur := &tc; len(cur.Children) > 0; {
+		depth++
+		cur = &cur.Children[0]
+	}
+
+	if depth != 9 {
+		t.Errorf("got depth: %d, want: %d", depth, 9)
+	}
+}
+
+// sequentialRun is a minimal `go test -json` stream for two independent,
+// sequentially-run top-level tests, with no subtests.
+const sequentialRun = `{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"pass","Package":"pkg","Test":"TestOne","Output":"--- PASS: TestOne\n"}
+{"Action":"run","Package":"pkg","Test":"TestTwo"}
+{"Action":"output","Package":"pkg","Test":"TestTwo","Output":"=== RUN   TestTwo\n"}
+{"Action":"fail","Package":"pkg","Test":"TestTwo","Output":"--- FAIL: TestTwo\n"}
+`
+
+// TestReader_Stream verifies that Stream emits each top-level test as soon
+// as its own terminal action is observed, in the order they resolve.
+func TestReader_Stream(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(sequentialRun))
+
+	out, errCh := r.Stream(context.Background())
+
+	var names []string
+	for tc := range out {
+		names = append(names, tc.Value.Name)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	want := []string{"TestOne", "TestTwo"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("got names: %v, want: %v", names, want)
+	}
+}
+
+// TestReader_ReadAllUsesStream verifies ReadAll still returns every
+// top-level test as a thin wrapper around Stream.
+func TestReader_ReadAllUsesStream(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(sequentialRun))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if len(set.Tests) != 2 {
+		t.Fatalf("got len(set.Tests): %d, want: %d", len(set.Tests), 2)
+	}
+}
+
+// TestReader_MaxLineSize verifies that a line longer than MaxLineSize
+// surfaces a scan error instead of silently truncating the event.
+func TestReader_MaxLineSize(t *testing.T) {
+	t.Parallel()
+
+	line := `{"Action":"output","Package":"pkg","Test":"TestOne","Output":"` +
+		strings.Repeat("x", 256) + `\n"}` + "\n"
+
+	r := NewReader(strings.NewReader(line), WithMaxLineSize(64))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if set.Err == nil {
+		t.Fatalf("got set.Err: %v, want: non-nil", set.Err)
+	}
+}
+
+// unterminatedRun is a stream where TestOne never reaches a terminal
+// action, as if the test process panicked or was killed mid-run.
+const unterminatedRun = `{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+`
+
+// TestReader_StreamFlushesUnterminatedTests verifies that a test still open
+// when the input ends is still emitted with whatever state it reached,
+// instead of being silently dropped.
+func TestReader_StreamFlushesUnterminatedTests(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(unterminatedRun))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if len(set.Tests) != 1 {
+		t.Fatalf("got len(set.Tests): %d, want: %d", len(set.Tests), 1)
+	}
+
+	if set.Tests[0].Value.Name != "TestOne" {
+		t.Errorf("got name: %q, want: %q", set.Tests[0].Value.Name, "TestOne")
+	}
+}
+
+// TestReader_ReadAllEmptyIsNonNil verifies ReadAll keeps returning a
+// non-nil, zero-length Tests slice when nothing resolves, matching the
+// pre-streaming behavior callers may depend on (e.g. json.Marshal'ing "[]"
+// rather than "null").
+func TestReader_ReadAllEmptyIsNonNil(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(""))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if set.Tests == nil {
+		t.Errorf("got set.Tests: nil, want: non-nil empty slice")
+	}
+}
+
+// matchedRun is a minimal stream with a top-level test that has one passing
+// and one flaky-named child, for exercising Include/Exclude globs.
+const matchedRun = `{"Action":"run","Package":"pkg","Test":"TestFoo"}
+{"Action":"run","Package":"pkg","Test":"TestFoo/ok"}
+{"Action":"pass","Package":"pkg","Test":"TestFoo/ok","Output":"--- PASS: TestFoo/ok\n"}
+{"Action":"run","Package":"pkg","Test":"TestFoo/TestFlakyThing"}
+{"Action":"pass","Package":"pkg","Test":"TestFoo/TestFlakyThing","Output":"--- PASS: TestFoo/TestFlakyThing\n"}
+{"Action":"pass","Package":"pkg","Test":"TestFoo","Output":"--- PASS: TestFoo\n"}
+`
+
+// TestReader_Exclude verifies that an Exclude glob drops the matched subtest
+// without dropping its unrelated sibling.
+func TestReader_Exclude(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(matchedRun), WithExclude("**/TestFlaky*"))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if len(set.Tests) != 1 {
+		t.Fatalf("got len(set.Tests): %d, want: %d", len(set.Tests), 1)
+	}
+
+	for _, child := range set.Tests[0].Children {
+		if strings.Contains(child.Value.Name, "TestFlaky") {
+			t.Errorf("got child: %s, want it excluded", child.Value.Name)
+		}
+	}
+}
+
+// TestReader_Include verifies that an Include glob keeps only the matched
+// package/test tree.
+func TestReader_Include(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(matchedRun), WithInclude("other/**"))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if len(set.Tests) != 0 {
+		t.Fatalf("got len(set.Tests): %d, want: %d", len(set.Tests), 0)
+	}
+}
+
+// TestReader_IncludeNestedSubtest verifies that an Include glob matching
+// only a nested subtest's key, not its parent's, still surfaces that
+// subtest instead of it being silently dropped once it's promoted to a
+// root of the prefix tree.
+func TestReader_IncludeNestedSubtest(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(matchedRun), WithInclude("pkg/TestFoo/ok"))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if len(set.Tests) != 1 {
+		t.Fatalf("got len(set.Tests): %d, want: %d", len(set.Tests), 1)
+	}
+
+	if set.Tests[0].Value.Name != "TestFoo/ok" {
+		t.Errorf("got name: %q, want: %q", set.Tests[0].Value.Name, "TestFoo/ok")
+	}
+}
+
+// TestReader_MatchFunc verifies that WithMatchFunc overrides Include/Exclude
+// glob matching entirely.
+func TestReader_MatchFunc(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(
+		strings.NewReader(matchedRun),
+		WithInclude("other/**"),
+		WithMatchFunc(func(pkg, name string) bool { return true }),
+	)
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if len(set.Tests) != 1 {
+		t.Fatalf("got len(set.Tests): %d, want: %d", len(set.Tests), 1)
+	}
+}
+
+// panicLog is a fixture captured from a real panicking test run: the test
+// never emits its own --- FAIL action, so the panic is all there is to go
+// on.
+const panicLog = `=== RUN   TestPanics
+panic: something went wrong
+
+goroutine 7 [running]:
+github.com/robotomize/go-allure/internal/gotest.doPanic(...)
+	/src/internal/gotest/reader.go:42 +0x25
+github.com/robotomize/go-allure/internal/gotest.TestPanics(0xc0000a4000)
+	/src/internal/gotest/reader_test.go:10 +0x39
+testing.tRunner(0xc0000a4000, 0x6b2f60)
+	/usr/local/go/src/testing/testing.go:1595 +0x216
+`
+
+// raceLog is a fixture captured from a real -race run.
+const raceLog = `=== RUN   TestRace
+==================
+WARNING: DATA RACE
+Write at 0x00c0000a4000 by goroutine 8:
+  github.com/robotomize/go-allure/internal/gotest.TestRace.func1()
+      /src/internal/gotest/reader_test.go:20 +0x44
+
+Previous read at 0x00c0000a4000 by goroutine 7:
+  github.com/robotomize/go-allure/internal/gotest.TestRace()
+      /src/internal/gotest/reader_test.go:25 +0x12
+
+Goroutine 8 (running) created at:
+  github.com/robotomize/go-allure/internal/gotest.TestRace()
+      /src/internal/gotest/reader_test.go:18 +0x30
+==================
+`
+
+// TestDetectPanic verifies the panic message, goroutine id, and stack frames
+// are parsed out of a test's captured output.
+func TestDetectPanic(t *testing.T) {
+	t.Parallel()
+
+	info := detectPanic([]byte(panicLog))
+	if info == nil {
+		t.Fatalf("got info: %v, want: non-nil", info)
+	}
+
+	if info.Message != "something went wrong" {
+		t.Errorf("got Message: %q, want: %q", info.Message, "something went wrong")
+	}
+
+	if info.Goroutine != "7" {
+		t.Errorf("got Goroutine: %q, want: %q", info.Goroutine, "7")
+	}
+
+	if len(info.Stack) != 3 {
+		t.Fatalf("got len(info.Stack): %d, want: %d", len(info.Stack), 3)
+	}
+
+	if info.Stack[0].File != "/src/internal/gotest/reader.go" || info.Stack[0].Line != 42 {
+		t.Errorf("got frame: %+v, want File: %q, Line: %d", info.Stack[0], "/src/internal/gotest/reader.go", 42)
+	}
+}
+
+// TestDetectPanic_NoMatch verifies ordinary output isn't mistaken for a
+// panic.
+func TestDetectPanic_NoMatch(t *testing.T) {
+	t.Parallel()
+
+	if info := detectPanic([]byte("--- PASS: TestOK\n")); info != nil {
+		t.Errorf("got info: %+v, want: nil", info)
+	}
+}
+
+// TestDetectDataRace verifies both conflicting access sites are parsed out
+// of a -race report.
+func TestDetectDataRace(t *testing.T) {
+	t.Parallel()
+
+	info := detectDataRace([]byte(raceLog))
+	if info == nil {
+		t.Fatalf("got info: %v, want: non-nil", info)
+	}
+
+	if info.Accesses[0].Operation != "Write" || info.Accesses[0].Goroutine != "8" {
+		t.Errorf("got Accesses[0]: %+v, want Operation: %q, Goroutine: %q", info.Accesses[0], "Write", "8")
+	}
+
+	if info.Accesses[1].Operation != "Previous read" || info.Accesses[1].Goroutine != "7" {
+		t.Errorf("got Accesses[1]: %+v, want Operation: %q, Goroutine: %q", info.Accesses[1], "Previous read", "7")
+	}
+
+	if len(info.Accesses[0].Stack) == 0 || len(info.Accesses[1].Stack) == 0 {
+		t.Errorf("got empty stack in one of the accesses: %+v", info.Accesses)
+	}
+}
+
+// TestDetectDataRace_NoMatch verifies ordinary output isn't mistaken for a
+// race report.
+func TestDetectDataRace_NoMatch(t *testing.T) {
+	t.Parallel()
+
+	if info := detectDataRace([]byte("--- PASS: TestOK\n")); info != nil {
+		t.Errorf("got info: %+v, want: nil", info)
+	}
+}
+
+// panicRun is a `go test -json` stream captured from a real panicking test:
+// the process exits mid-run, so TestPanics never gets its own terminal
+// action.
+const panicRun = `{"Action":"run","Package":"pkg","Test":"TestPanics"}
+{"Action":"output","Package":"pkg","Test":"TestPanics","Output":"=== RUN   TestPanics\n"}
+{"Action":"output","Package":"pkg","Test":"TestPanics","Output":"panic: something went wrong\n"}
+{"Action":"output","Package":"pkg","Test":"TestPanics","Output":"\n"}
+{"Action":"output","Package":"pkg","Test":"TestPanics","Output":"goroutine 7 [running]:\n"}
+{"Action":"output","Package":"pkg","Test":"TestPanics","Output":"pkg.TestPanics(0xc0000a4000)\n"}
+{"Action":"output","Package":"pkg","Test":"TestPanics","Output":"\t/src/pkg/panic_test.go:10 +0x39\n"}
+`
+
+// TestReader_ReadAllSynthesizesPanicFailure drives panicRun end-to-end
+// through ReadAll. TestPanics never reaches its own terminal action, so
+// without the end-of-stream flush in Stream it would be dropped before
+// detectPanic ever ran; this exercises the flush and the synthesized
+// failure status together.
+func TestReader_ReadAllSynthesizesPanicFailure(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(panicRun))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if len(set.Tests) != 1 {
+		t.Fatalf("got len(set.Tests): %d, want: %d", len(set.Tests), 1)
+	}
+
+	tc := set.Tests[0]
+
+	if tc.Panic == nil {
+		t.Fatalf("got Panic: %v, want: non-nil", tc.Panic)
+	}
+
+	if tc.Panic.Message != "something went wrong" {
+		t.Errorf("got Panic.Message: %q, want: %q", tc.Panic.Message, "something went wrong")
+	}
+
+	if tc.Value.Status != ActionFail {
+		t.Errorf("got Status: %q, want: %q", tc.Value.Status, ActionFail)
+	}
+}
+
+// panicWithIndentRunRun is panicRun's single-test shape again, but with a
+// panic message containing a run of 4 spaces, the same width as
+// whitespaceIndent. With no --- PASS/FAIL/SKIP row anywhere in this
+// subtree's log, the dedent pass has nothing to strip against and must not
+// treat that as "strip every occurrence".
+const panicWithIndentRunRun = `{"Action":"run","Package":"pkg","Test":"TestPanics"}
+{"Action":"output","Package":"pkg","Test":"TestPanics","Output":"=== RUN   TestPanics\n"}
+{"Action":"output","Package":"pkg","Test":"TestPanics","Output":"panic: want \"a    b\", got \"a   c\"\n"}
+`
+
+// TestReader_ReadAllPreservesIndentRunsInPanicMessage verifies that a run of
+// whitespaceIndent-width spaces inside a panic message survives intact when
+// the subtree has no result row to dedent against.
+func TestReader_ReadAllPreservesIndentRunsInPanicMessage(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(panicWithIndentRunRun))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if len(set.Tests) != 1 {
+		t.Fatalf("got len(set.Tests): %d, want: %d", len(set.Tests), 1)
+	}
+
+	tc := set.Tests[0]
+
+	if tc.Panic == nil {
+		t.Fatalf("got Panic: %v, want: non-nil", tc.Panic)
+	}
+
+	want := `want "a    b", got "a   c"`
+	if tc.Panic.Message != want {
+		t.Errorf("got Panic.Message: %q, want: %q", tc.Panic.Message, want)
+	}
+}
+
+// raceRun is a `go test -json` stream captured from a real -race run.
+const raceRun = `{"Action":"run","Package":"pkg","Test":"TestRace"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"=== RUN   TestRace\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"==================\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"WARNING: DATA RACE\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"Write at 0x00c0000a4000 by goroutine 8:\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"  pkg.TestRace.func1()\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"      /src/pkg/race_test.go:20 +0x44\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"Previous read at 0x00c0000a4000 by goroutine 7:\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"  pkg.TestRace()\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"      /src/pkg/race_test.go:25 +0x12\n"}
+{"Action":"output","Package":"pkg","Test":"TestRace","Output":"==================\n"}
+{"Action":"fail","Package":"pkg","Test":"TestRace","Output":"--- FAIL: TestRace\n"}
+`
+
+// TestReader_ReadAllSurfacesDataRace drives raceRun end-to-end through
+// ReadAll and verifies the race is parsed onto the resolved NestedTest.
+func TestReader_ReadAllSurfacesDataRace(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader(raceRun))
+
+	set, err := r.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("got err: %v, want: %v", err, nil)
+	}
+
+	if len(set.Tests) != 1 {
+		t.Fatalf("got len(set.Tests): %d, want: %d", len(set.Tests), 1)
+	}
+
+	tc := set.Tests[0]
+
+	if tc.DataRace == nil {
+		t.Fatalf("got DataRace: %v, want: non-nil", tc.DataRace)
+	}
+
+	if tc.DataRace.Accesses[0].Goroutine != "8" || tc.DataRace.Accesses[1].Goroutine != "7" {
+		t.Errorf("got Accesses: %+v, want Goroutine 8 then 7", tc.DataRace.Accesses)
+	}
+}